@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Crash-loop backoff policy: a process that keeps dying gets restarted
+// with exponential delay rather than hot-looping, and gives up entirely
+// once it has restarted too many times within a rolling window.
+const (
+	restartBackoffBase   = 500 * time.Millisecond
+	restartBackoffFactor = 2.0
+	restartBackoffCap    = 30 * time.Second
+	restartWindow        = time.Minute      // rolling window the restart count is measured over
+	restartMaxCount      = 5                // restarts allowed within restartWindow before giving up
+	restartResetAfter    = 60 * time.Second // uptime after which backoff forgets past failures
+)
+
+// backoffState is the crash-loop bookkeeping for a single (idx, procNum)
+// replica, so that concurrent replicas of the same process type back off
+// independently.
+type backoffState struct {
+	mu        sync.Mutex
+	attempts  int
+	restarts  []time.Time
+	startedAt time.Time
+}
+
+// backoffFor returns the backoff state for key, creating it on first use.
+func (f *Forego) backoffFor(key procKey) *backoffState {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.backoffForLocked(key)
+}
+
+// backoffForLocked is backoffFor for callers that already hold f.mu.
+func (f *Forego) backoffForLocked(key procKey) *backoffState {
+	if f.backoff == nil {
+		f.backoff = make(map[procKey]*backoffState)
+	}
+	bs, ok := f.backoff[key]
+	if !ok {
+		bs = &backoffState{}
+		f.backoff[key] = bs
+	}
+	return bs
+}
+
+// recordStart notes that the replica just launched, resetting the backoff
+// counter if it had stayed up long enough to count as healthy.
+func (bs *backoffState) recordStart() {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if !bs.startedAt.IsZero() && time.Since(bs.startedAt) >= restartResetAfter {
+		bs.attempts = 0
+		bs.restarts = nil
+	}
+	bs.startedAt = time.Now()
+}
+
+// count reports how many restarts this replica has made so far, for
+// `forego ps` and friends.
+func (bs *backoffState) count() int {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	return bs.attempts
+}
+
+// nextDelay reports how long to wait before the next restart, and whether
+// one is allowed at all under the rolling restartMaxCount budget.
+func (bs *backoffState) nextDelay() (time.Duration, bool) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-restartWindow)
+	kept := bs.restarts[:0]
+	for _, t := range bs.restarts {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	bs.restarts = kept
+
+	if len(bs.restarts) >= restartMaxCount {
+		return 0, false
+	}
+	bs.restarts = append(bs.restarts, now)
+
+	attempt := bs.attempts
+	bs.attempts++
+
+	delay := time.Duration(float64(restartBackoffBase) * math.Pow(restartBackoffFactor, float64(attempt)))
+	if delay > restartBackoffCap {
+		delay = restartBackoffCap
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay)/4 + 1))
+	return delay + jitter, true
+}
+
+// restart applies the crash-loop backoff policy for key: it waits out the
+// computed delay and reports true if the caller should actually restart
+// the process. If the restart budget has been exhausted it logs and, per
+// --on-failure, either triggers a full shutdown or leaves the replica dead
+// while the rest of the app keeps running.
+func (f *Forego) restart(key procKey, procName string, of *OutletFactory) bool {
+	delay, ok := f.backoffFor(key).nextDelay()
+	if !ok {
+		f.setState(key, "failed")
+		of.SystemOutput(fmt.Sprintf("%s restarted too many times, giving up", procName))
+		if flagOnFailure == "shutdown" {
+			f.SignalShutdown()
+		}
+		return false
+	}
+
+	f.setState(key, "restarting")
+	if delay > 0 {
+		of.SystemOutput(fmt.Sprintf("%s crashed, restarting in %s", procName, delay))
+		// A bare time.Sleep here would ignore ctrl-c for up to delay (tens
+		// of seconds at the high end of the backoff curve): this runs in
+		// the per-replica goroutine that f.wg.Wait() blocks on, so select
+		// on teardown the same way terminateProcess does.
+		select {
+		case <-time.After(delay):
+		case <-f.teardownNow:
+			return false
+		case <-f.teardown:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffStateNextDelayCapsAttempts(t *testing.T) {
+	bs := &backoffState{}
+
+	for i := 0; i < restartMaxCount; i++ {
+		if _, ok := bs.nextDelay(); !ok {
+			t.Fatalf("attempt %d: expected ok=true within restartMaxCount budget", i)
+		}
+	}
+
+	if _, ok := bs.nextDelay(); ok {
+		t.Fatal("expected ok=false once restartMaxCount restarts have happened within the window")
+	}
+}
+
+func TestBackoffStateNextDelayWindowExpiry(t *testing.T) {
+	bs := &backoffState{}
+
+	// Fill the budget with restarts that are already outside restartWindow.
+	for i := 0; i < restartMaxCount; i++ {
+		bs.restarts = append(bs.restarts, time.Now().Add(-restartWindow-time.Second))
+	}
+
+	if _, ok := bs.nextDelay(); !ok {
+		t.Fatal("expected ok=true: all prior restarts are outside the rolling window")
+	}
+}
+
+func TestBackoffStateRecordStartResetsAfterHealthyUptime(t *testing.T) {
+	bs := &backoffState{
+		attempts:  3,
+		restarts:  []time.Time{time.Now()},
+		startedAt: time.Now().Add(-restartResetAfter - time.Second),
+	}
+
+	bs.recordStart()
+
+	if bs.attempts != 0 {
+		t.Errorf("attempts = %d, want 0 after a healthy-enough uptime", bs.attempts)
+	}
+	if bs.restarts != nil {
+		t.Errorf("restarts = %v, want nil after a healthy-enough uptime", bs.restarts)
+	}
+}
+
+func TestBackoffStateRecordStartKeepsCountWhenUnhealthy(t *testing.T) {
+	bs := &backoffState{
+		attempts:  3,
+		startedAt: time.Now().Add(-time.Millisecond),
+	}
+
+	bs.recordStart()
+
+	if bs.attempts != 3 {
+		t.Errorf("attempts = %d, want 3: uptime was well under restartResetAfter", bs.attempts)
+	}
+}
+
+func TestBackoffStateCount(t *testing.T) {
+	bs := &backoffState{}
+
+	if got := bs.count(); got != 0 {
+		t.Fatalf("count() = %d, want 0 on a fresh backoffState", got)
+	}
+
+	bs.nextDelay()
+	bs.nextDelay()
+
+	if got := bs.count(); got != 2 {
+		t.Fatalf("count() = %d, want 2 after two nextDelay calls", got)
+	}
+}
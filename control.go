@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// controlRequest is the JSON-over-unix-socket RPC sent by the `forego ps`,
+// `forego restart`, `forego scale`, `forego kill`, and `forego logs`
+// subcommands to a running `forego start --control-sock`.
+type controlRequest struct {
+	Action string // "ps", "restart", "scale", "kill", or "logs"
+	Name   string // process name, for restart/kill/logs
+	Arg    string // "name=count" for scale, a signal name for kill, a line count for logs
+}
+
+// processStatus is one line of `forego ps` output.
+type processStatus struct {
+	Name     string
+	Num      int
+	Pid      int
+	Uptime   string
+	Restarts int
+	State    string // "running", "unhealthy", "restarting", "stopping", or "failed"
+}
+
+type controlResponse struct {
+	Error     string          `json:",omitempty"`
+	Processes []processStatus `json:",omitempty"`
+	Lines     []string        `json:",omitempty"`
+}
+
+// signalsByName covers the signals a process supervisor is actually asked
+// for in practice. signalByName falls back to parsing Arg as a bare signal
+// number for anything else.
+var signalsByName = map[string]syscall.Signal{
+	"TERM":  syscall.SIGTERM,
+	"KILL":  syscall.SIGKILL,
+	"HUP":   syscall.SIGHUP,
+	"INT":   syscall.SIGINT,
+	"QUIT":  syscall.SIGQUIT,
+	"USR1":  syscall.SIGUSR1,
+	"USR2":  syscall.SIGUSR2,
+	"WINCH": syscall.SIGWINCH,
+	"CONT":  syscall.SIGCONT,
+	"STOP":  syscall.SIGSTOP,
+	"ABRT":  syscall.SIGABRT,
+	"ALRM":  syscall.SIGALRM,
+}
+
+// serveControlSock listens on flagControlSock and answers controlRequests
+// against f until forego tears down. A stale socket file left behind by a
+// previous, uncleanly-killed run is removed first.
+func (f *Forego) serveControlSock() {
+	os.Remove(flagControlSock)
+
+	l, err := net.Listen("unix", flagControlSock)
+	if err != nil {
+		f.of.SystemOutput(fmt.Sprintf("control socket: %s", err))
+		return
+	}
+	defer os.Remove(flagControlSock)
+
+	go func() {
+		<-f.teardown
+		l.Close()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go f.handleControlConn(conn)
+	}
+}
+
+func (f *Forego) handleControlConn(conn net.Conn) {
+	defer conn.Close()
+
+	var req controlRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		json.NewEncoder(conn).Encode(controlResponse{Error: err.Error()})
+		return
+	}
+
+	json.NewEncoder(conn).Encode(f.handleControlRequest(req))
+}
+
+func (f *Forego) handleControlRequest(req controlRequest) controlResponse {
+	switch req.Action {
+	case "ps":
+		return controlResponse{Processes: f.snapshot()}
+
+	case "restart":
+		if err := f.restartByName(req.Name); err != nil {
+			return controlResponse{Error: err.Error()}
+		}
+		return controlResponse{}
+
+	case "scale":
+		if err := f.scale(req.Arg); err != nil {
+			return controlResponse{Error: err.Error()}
+		}
+		return controlResponse{}
+
+	case "kill":
+		if err := f.signalByName(req.Name, req.Arg); err != nil {
+			return controlResponse{Error: err.Error()}
+		}
+		return controlResponse{}
+
+	case "logs":
+		lines, err := f.tailLogs(req.Name, req.Arg)
+		if err != nil {
+			return controlResponse{Error: err.Error()}
+		}
+		return controlResponse{Lines: lines}
+
+	default:
+		return controlResponse{Error: fmt.Sprintf("unknown action %q", req.Action)}
+	}
+}
+
+// snapshot lists every currently running replica for `forego ps`.
+func (f *Forego) snapshot() []processStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := make([]processStatus, 0, len(f.procs))
+	for key, rp := range f.procs {
+		uptime := ""
+		if !rp.startedAt.IsZero() {
+			uptime = time.Since(rp.startedAt).Round(time.Second).String()
+		}
+		out = append(out, processStatus{
+			Name:     key.name,
+			Num:      key.num + 1,
+			Pid:      rp.pid,
+			Uptime:   uptime,
+			Restarts: f.backoffForLocked(key).count(),
+			State:    rp.state,
+		})
+	}
+	return out
+}
+
+// entryByName finds a Procfile entry and its index by name, under f.mu.
+func (f *Forego) entryByName(name string) (int, ProcfileEntry, bool) {
+	for idx, e := range f.pf.Entries {
+		if e.Name == name {
+			return idx, e, true
+		}
+	}
+	return 0, ProcfileEntry{}, false
+}
+
+// restartByName stops and relaunches every running replica of name,
+// waiting for each old replica to actually exit before starting its
+// replacement so the replacement isn't racing the old one for the port.
+func (f *Forego) restartByName(name string) error {
+	f.mu.Lock()
+	idx, proc, ok := f.entryByName(name)
+	env, of := f.env, f.of
+	var nums []int
+	var replaced []*runningProc
+	for key, rp := range f.procs {
+		if key.name == name {
+			nums = append(nums, key.num)
+			replaced = append(replaced, rp)
+			rp.closeStop()
+		}
+	}
+	f.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("no such process: %s", name)
+	}
+
+	for i, num := range nums {
+		f.waitForExit(replaced[i])
+		of.SystemOutput(fmt.Sprintf("restarting %s.%d by request", name, num+1))
+		f.startProcess(idx, num, proc, env, of)
+	}
+	return nil
+}
+
+// scale re-applies a single "name=count" pair at runtime, starting new
+// replicas or stopping extras to match count, rewiring what -c set at
+// startup.
+func (f *Forego) scale(arg string) error {
+	updates, err := parseConcurrency(arg)
+	if err != nil {
+		return err
+	}
+
+	for name, count := range updates {
+		f.mu.Lock()
+		idx, proc, ok := f.entryByName(name)
+		env, of := f.env, f.of
+		f.mu.Unlock()
+
+		if !ok {
+			return fmt.Errorf("no such process: %s", name)
+		}
+
+		for i := 0; i < count; i++ {
+			key := procKey{name, i}
+			f.mu.Lock()
+			_, running := f.procs[key]
+			f.mu.Unlock()
+
+			if !running {
+				of.SystemOutput(fmt.Sprintf("scaling %s to %d, starting %s.%d", name, count, name, i+1))
+				f.startProcess(idx, i, proc, env, of)
+			}
+		}
+		f.stopReplicasFrom(name, count)
+
+		// Persist the new count so a later SIGHUP reload re-applies it
+		// instead of reverting to what -c set at startup.
+		f.mu.Lock()
+		if f.scaled == nil {
+			f.scaled = make(map[string]int)
+		}
+		f.scaled[name] = count
+		f.mu.Unlock()
+	}
+	return nil
+}
+
+// tailLogs returns the buffered log lines for every running replica of
+// name, most recent last. countArg is the requested line count ("" or a
+// non-positive value means "everything buffered").
+func (f *Forego) tailLogs(name, countArg string) ([]string, error) {
+	count := 0
+	if countArg != "" {
+		n, err := strconv.Atoi(countArg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid line count %q", countArg)
+		}
+		count = n
+	}
+
+	f.mu.Lock()
+	_, _, ok := f.entryByName(name)
+	of := f.of
+	var nums []int
+	for key := range f.procs {
+		if key.name == name {
+			nums = append(nums, key.num)
+		}
+	}
+	f.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("no such process: %s", name)
+	}
+
+	var lines []string
+	for _, num := range nums {
+		lines = append(lines, of.TailLines(fmt.Sprintf("%s.%d", name, num+1), count)...)
+	}
+	return lines, nil
+}
+
+// signalByName sends a signal, by name (TERM, KILL, ...) or bare number, to
+// every running replica of name.
+func (f *Forego) signalByName(name, sigName string) error {
+	sig, ok := signalsByName[strings.ToUpper(sigName)]
+	if !ok {
+		n, err := strconv.Atoi(sigName)
+		if err != nil {
+			return fmt.Errorf("unknown signal %q", sigName)
+		}
+		sig = syscall.Signal(n)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sent := false
+	for key, rp := range f.procs {
+		if key.name == name && rp.pid != 0 {
+			syscall.Kill(rp.pid, sig)
+			sent = true
+		}
+	}
+	if !sent {
+		return fmt.Errorf("no running process named %s", name)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// flagControlSockClient is shared by every control-plane subcommand below; it
+// must match the --control-sock a `forego start` was launched with.
+var flagControlSockClient string
+
+func registerControlSockFlag(cmd *Command) {
+	cmd.Flag.StringVar(&flagControlSockClient, "control-sock", "/tmp/forego.sock", "control socket of the running `forego start`")
+}
+
+// controlCall sends req to the control socket and decodes its response.
+func controlCall(req controlRequest) (controlResponse, error) {
+	conn, err := net.Dial("unix", flagControlSockClient)
+	if err != nil {
+		return controlResponse{}, fmt.Errorf("connecting to %s: %w", flagControlSockClient, err)
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return controlResponse{}, err
+	}
+
+	var resp controlResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return controlResponse{}, err
+	}
+	if resp.Error != "" {
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
+
+var cmdPs = &Command{
+	Run:   runPs,
+	Usage: "ps [--control-sock path]",
+	Short: "List the processes of a running forego start",
+	Long: `
+List the processes of a running "forego start", as reported over its
+control socket: pid, uptime, restart count, and lifecycle state.
+`,
+}
+
+var cmdRestart = &Command{
+	Run:   runRestart,
+	Usage: "restart <name> [--control-sock path]",
+	Short: "Restart every replica of a process by name",
+	Long: `
+Restart every running replica of the named process type, over the control
+socket of a running "forego start".
+`,
+}
+
+var cmdScale = &Command{
+	Run:   runScale,
+	Usage: "scale <name>=<count> [--control-sock path]",
+	Short: "Scale a process type up or down at runtime",
+	Long: `
+Rewire the concurrency of a process type while "forego start" is running,
+starting or stopping replicas to match, e.g.:
+
+  forego scale web=3
+`,
+}
+
+var flagKillSignal string
+
+var cmdKill = &Command{
+	Run:   runKill,
+	Usage: "kill <name> [-s SIGNAL] [--control-sock path]",
+	Short: "Send a signal to every replica of a process by name",
+}
+
+var flagLogsLines int
+
+var cmdLogs = &Command{
+	Run:   runLogs,
+	Usage: "logs <name> [-n lines] [--control-sock path]",
+	Short: "Tail the buffered output of a process by name",
+	Long: `
+Print the most recently buffered output lines for every running replica of
+the named process, over the control socket of a running "forego start".
+Only a bounded number of the most recent lines are kept in memory; this is
+not a substitute for --log-file.
+`,
+}
+
+func init() {
+	registerControlSockFlag(cmdPs)
+	registerControlSockFlag(cmdRestart)
+	registerControlSockFlag(cmdScale)
+	registerControlSockFlag(cmdKill)
+	registerControlSockFlag(cmdLogs)
+	cmdKill.Flag.StringVar(&flagKillSignal, "s", "TERM", "signal to send")
+	cmdLogs.Flag.IntVar(&flagLogsLines, "n", 0, "number of lines to print (default: everything buffered)")
+}
+
+func runPs(cmd *Command, args []string) {
+	resp, err := controlCall(controlRequest{Action: "ps"})
+	handleError(err)
+
+	for _, p := range resp.Processes {
+		fmt.Printf("%-20s pid=%-8d uptime=%-10s restarts=%-3d state=%s\n",
+			fmt.Sprintf("%s.%d", p.Name, p.Num), p.Pid, p.Uptime, p.Restarts, p.State)
+	}
+}
+
+func runRestart(cmd *Command, args []string) {
+	if len(args) != 1 {
+		handleError(errors.New("usage: forego restart <name>"))
+	}
+	_, err := controlCall(controlRequest{Action: "restart", Name: args[0]})
+	handleError(err)
+}
+
+func runScale(cmd *Command, args []string) {
+	if len(args) != 1 {
+		handleError(errors.New("usage: forego scale <name>=<count>"))
+	}
+	_, err := controlCall(controlRequest{Action: "scale", Arg: args[0]})
+	handleError(err)
+}
+
+func runKill(cmd *Command, args []string) {
+	if len(args) != 1 {
+		handleError(errors.New("usage: forego kill <name> [-s SIGNAL]"))
+	}
+	_, err := controlCall(controlRequest{Action: "kill", Name: args[0], Arg: flagKillSignal})
+	handleError(err)
+}
+
+func runLogs(cmd *Command, args []string) {
+	if len(args) != 1 {
+		handleError(errors.New("usage: forego logs <name> [-n lines]"))
+	}
+
+	arg := ""
+	if flagLogsLines > 0 {
+		arg = fmt.Sprint(flagLogsLines)
+	}
+
+	resp, err := controlCall(controlRequest{Action: "logs", Name: args[0], Arg: arg})
+	handleError(err)
+
+	for _, line := range resp.Lines {
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+func newTestForego() *Forego {
+	return &Forego{
+		procs: make(map[procKey]*runningProc),
+		pf:    &Procfile{},
+		of:    NewOutletFactory(),
+	}
+}
+
+func TestHandleControlRequestUnknownAction(t *testing.T) {
+	f := newTestForego()
+
+	resp := f.handleControlRequest(controlRequest{Action: "nope"})
+
+	if resp.Error == "" {
+		t.Fatal("expected an error for an unrecognized action")
+	}
+}
+
+func TestHandleControlRequestPsEmpty(t *testing.T) {
+	f := newTestForego()
+
+	resp := f.handleControlRequest(controlRequest{Action: "ps"})
+
+	if resp.Error != "" {
+		t.Fatalf("unexpected error: %s", resp.Error)
+	}
+	if len(resp.Processes) != 0 {
+		t.Fatalf("Processes = %v, want empty with nothing running", resp.Processes)
+	}
+}
+
+func TestHandleControlRequestScaleBadArg(t *testing.T) {
+	f := newTestForego()
+
+	resp := f.handleControlRequest(controlRequest{Action: "scale", Arg: "not-a-valid-pair"})
+
+	if resp.Error == "" {
+		t.Fatal("expected an error for a malformed scale argument")
+	}
+}
+
+func TestHandleControlRequestKillUnknownProcess(t *testing.T) {
+	f := newTestForego()
+
+	resp := f.handleControlRequest(controlRequest{Action: "kill", Name: "web", Arg: "TERM"})
+
+	if resp.Error == "" {
+		t.Fatal("expected an error killing a process that isn't running")
+	}
+}
+
+func TestHandleControlRequestLogsUnknownProcess(t *testing.T) {
+	f := newTestForego()
+
+	resp := f.handleControlRequest(controlRequest{Action: "logs", Name: "web"})
+
+	if resp.Error == "" {
+		t.Fatal("expected an error tailing logs for a process that doesn't exist")
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// HealthCheck describes how to probe a running replica for liveness: an
+// HTTP GET against $PORT, a bare TCP connect to $PORT, or the exit status
+// of an arbitrary shell command. It is attached to a ProcfileEntry by the
+// Procfile parser from a line's health_check directive.
+type HealthCheck struct {
+	Type          string // "http", "tcp", or "exec"
+	Target        string // URL path for "http"; shell command for "exec"; unused for "tcp"
+	Interval      time.Duration
+	Timeout       time.Duration
+	FailThreshold int // consecutive failures before the replica is considered unhealthy
+}
+
+// probe runs a single check against the replica listening on port. A nil
+// error means healthy.
+func (hc *HealthCheck) probe(port int) error {
+	switch hc.Type {
+	case "http":
+		client := http.Client{Timeout: hc.Timeout}
+		resp, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d%s", port, hc.Target))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("health check returned %s", resp.Status)
+		}
+		return nil
+
+	case "tcp":
+		conn, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", port), hc.Timeout)
+		if err != nil {
+			return err
+		}
+		conn.Close()
+		return nil
+
+	case "exec":
+		cmd := exec.Command("sh", "-c", hc.Target)
+		cmd.Env = append(os.Environ(), fmt.Sprintf("PORT=%d", port))
+		return cmd.Run()
+
+	default:
+		return fmt.Errorf("unknown health check type %q", hc.Type)
+	}
+}
+
+// monitorHealth polls hc on an interval until the replica becomes
+// unhealthy or finished closes (the replica exited, by crash, reload, or
+// restart), so it never outlives the replica it's probing. The first
+// successful probe marks name ready, unlocking any process that declared
+// a depends_on on it. FailThreshold consecutive failures closes unhealthy,
+// on which startProcess restarts the replica under -r; without -r the
+// replica is left running, marked unhealthy.
+func (f *Forego) monitorHealth(procName string, key procKey, port int, hc *HealthCheck, unhealthy, finished chan struct{}, of *OutletFactory) {
+	fails := 0
+	ticker := time.NewTicker(hc.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-finished:
+			return
+
+		case <-ticker.C:
+			if err := hc.probe(port); err != nil {
+				fails++
+				of.SystemOutput(fmt.Sprintf("%s health check failed (%d/%d): %s", procName, fails, hc.FailThreshold, err))
+				if fails >= hc.FailThreshold {
+					of.SystemOutput(fmt.Sprintf("%s is unhealthy", procName))
+					f.setState(key, "unhealthy")
+					close(unhealthy)
+					return
+				}
+				continue
+			}
+
+			if fails > 0 {
+				of.SystemOutput(fmt.Sprintf("%s recovered", procName))
+			}
+			fails = 0
+			f.markReady(key.name)
+		}
+	}
+}
+
+// readyChan returns the channel that's closed once a process named name
+// reports ready, creating it on first reference so that a dependent
+// process can wait on it before its upstream has even started.
+func (f *Forego) readyChan(name string) chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.ready == nil {
+		f.ready = make(map[string]chan struct{})
+	}
+	if ch, ok := f.ready[name]; ok {
+		return ch
+	}
+
+	ch := make(chan struct{})
+	f.ready[name] = ch
+	return ch
+}
+
+// markReady signals that name is ready, unblocking any startWhenReady
+// calls waiting on it. Safe to call more than once.
+func (f *Forego) markReady(name string) {
+	ch := f.readyChan(name)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	select {
+	case <-ch:
+	default:
+		close(ch)
+	}
+}
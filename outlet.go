@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tailBufferLines is how many of a process's most recent lines `forego
+// logs` can retrieve, per process name.
+const tailBufferLines = 500
+
+// OutletFactory creates the per-process, per-stream writers that every
+// supervised process's stdout/stderr is wired to, and also handles
+// forego's own system and error messages, so the whole run's output goes
+// through one consistently-formatted, optionally-rotated place.
+type OutletFactory struct {
+	Padding int
+	Format  string // "text" (default) or "json"
+
+	LogFile       string
+	LogMaxSize    int // MB, 0 means no rotation
+	LogMaxBackups int
+
+	mu       sync.Mutex
+	file     *os.File // open handle to LogFile, nil until first write
+	fileSize int64
+	tails    map[string]*ringBuffer // recent lines, keyed by process name, for `forego logs`
+}
+
+// NewOutletFactory returns an OutletFactory that writes unrotated text to
+// stdout/stderr until configured otherwise.
+func NewOutletFactory() *OutletFactory {
+	return &OutletFactory{Format: "text", tails: make(map[string]*ringBuffer)}
+}
+
+// ringBuffer keeps the last n lines written to it, oldest discarded first.
+type ringBuffer struct {
+	lines []string
+	next  int
+	full  bool
+}
+
+func newRingBuffer(n int) *ringBuffer {
+	return &ringBuffer{lines: make([]string, n)}
+}
+
+func (rb *ringBuffer) add(line string) {
+	rb.lines[rb.next] = line
+	rb.next = (rb.next + 1) % len(rb.lines)
+	if rb.next == 0 {
+		rb.full = true
+	}
+}
+
+// snapshot returns the buffered lines in the order they were written.
+func (rb *ringBuffer) snapshot() []string {
+	if !rb.full {
+		return append([]string(nil), rb.lines[:rb.next]...)
+	}
+	out := make([]string, 0, len(rb.lines))
+	out = append(out, rb.lines[rb.next:]...)
+	out = append(out, rb.lines[:rb.next]...)
+	return out
+}
+
+// Outlet is the io.Writer a supervised process's Stdout or Stderr is
+// pointed at.
+type Outlet struct {
+	of       *OutletFactory
+	procName string
+	index    int
+	isError  bool
+	rp       *runningProc // for the pid in JSON records; may still be 0 at CreateOutlet time
+}
+
+// CreateOutlet returns the writer for one process's stdout (isError
+// false) or stderr (isError true) stream. rp is consulted for the
+// replica's pid, which may not be known yet when the outlet is created.
+func (of *OutletFactory) CreateOutlet(procName string, index int, isError bool, rp *runningProc) *Outlet {
+	return &Outlet{of: of, procName: procName, index: index, isError: isError, rp: rp}
+}
+
+func (o *Outlet) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		pid := 0
+		if o.rp != nil {
+			pid = o.rp.pid
+		}
+		o.of.writeLine(o.procName, o.index, o.isError, pid, line)
+	}
+	return len(p), nil
+}
+
+// jsonRecord is one line of --log-format=json output.
+type jsonRecord struct {
+	Ts      string `json:"ts"`
+	Process string `json:"process"`
+	Replica int    `json:"replica"`
+	Stream  string `json:"stream"`
+	Msg     string `json:"msg"`
+	Pid     int    `json:"pid"`
+}
+
+// writeLine formats one line of process output or a system message
+// according to of.Format and sends it to the configured destination.
+func (of *OutletFactory) writeLine(procName string, index int, isError bool, pid int, msg string) {
+	var out string
+
+	if of.Format == "json" {
+		stream := "stdout"
+		if isError {
+			stream = "stderr"
+		}
+		b, err := json.Marshal(jsonRecord{
+			Ts:      time.Now().Format(time.RFC3339Nano),
+			Process: procName,
+			Replica: index,
+			Stream:  stream,
+			Msg:     msg,
+			Pid:     pid,
+		})
+		if err != nil {
+			return
+		}
+		out = string(b) + "\n"
+	} else {
+		out = fmt.Sprintf("%-*s | %s\n", of.Padding, procName, msg)
+	}
+
+	of.recordTail(procName, msg)
+	of.write(isError, []byte(out))
+}
+
+// recordTail appends msg to procName's tail ring buffer, for later
+// retrieval by TailLines (the "forego logs" control-socket action).
+func (of *OutletFactory) recordTail(procName, msg string) {
+	of.mu.Lock()
+	defer of.mu.Unlock()
+
+	if of.tails == nil {
+		of.tails = make(map[string]*ringBuffer)
+	}
+	rb, ok := of.tails[procName]
+	if !ok {
+		rb = newRingBuffer(tailBufferLines)
+		of.tails[procName] = rb
+	}
+	rb.add(msg)
+}
+
+// TailLines returns up to the last n lines written for procName (which is
+// "name.replica", matching what CreateOutlet was called with, or "system"
+// for forego's own messages). If n <= 0 or exceeds what's buffered, all
+// buffered lines are returned.
+func (of *OutletFactory) TailLines(procName string, n int) []string {
+	of.mu.Lock()
+	rb, ok := of.tails[procName]
+	of.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	lines := rb.snapshot()
+	if n > 0 && n < len(lines) {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// write sends a fully-formatted line either to the rotating log file, or
+// to stdout/stderr if no --log-file was configured.
+func (of *OutletFactory) write(isError bool, line []byte) {
+	if of.LogFile == "" {
+		if isError {
+			os.Stderr.Write(line)
+		} else {
+			os.Stdout.Write(line)
+		}
+		return
+	}
+
+	of.mu.Lock()
+	defer of.mu.Unlock()
+
+	if err := of.rotateLocked(len(line)); err != nil {
+		os.Stderr.Write(line) // best effort: don't lose the line if rotation failed
+		return
+	}
+
+	n, _ := of.file.Write(line)
+	of.fileSize += int64(n)
+}
+
+// rotateLocked opens LogFile if it isn't open yet, and rotates it to
+// LogFile.1, LogFile.2, ... (keeping at most LogMaxBackups) if writing n
+// more bytes would push it past LogMaxSize. Caller must hold of.mu.
+func (of *OutletFactory) rotateLocked(n int) error {
+	maxBytes := int64(of.LogMaxSize) * 1024 * 1024
+
+	if of.file != nil && of.LogMaxSize > 0 && of.fileSize+int64(n) > maxBytes {
+		of.file.Close()
+		of.file = nil
+
+		if of.LogMaxBackups > 0 {
+			for i := of.LogMaxBackups - 1; i >= 1; i-- {
+				os.Rename(fmt.Sprintf("%s.%d", of.LogFile, i), fmt.Sprintf("%s.%d", of.LogFile, i+1))
+			}
+			os.Rename(of.LogFile, fmt.Sprintf("%s.1", of.LogFile))
+		} else {
+			os.Remove(of.LogFile)
+		}
+	}
+
+	if of.file == nil {
+		f, err := os.OpenFile(of.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return err
+		}
+		of.file = f
+		of.fileSize = info.Size()
+	}
+
+	return nil
+}
+
+// SystemOutput prints a forego-internal message the same way a process's
+// own output would be: padded and prefixed with "system" in text mode, or
+// as a JSON record with process="system".
+func (of *OutletFactory) SystemOutput(msg string) {
+	of.writeLine("system", 0, false, os.Getpid(), msg)
+}
+
+// ErrorOutput prints msg like SystemOutput, but to stderr/with
+// stream="stderr", and then exits forego with a non-zero status: it's
+// used for startup errors that make continuing pointless.
+func (of *OutletFactory) ErrorOutput(msg string) {
+	of.writeLine("system", 0, true, os.Getpid(), msg)
+	os.Exit(1)
+}
+
+// io.Writer is implemented by Outlet; verify at compile time.
+var _ io.Writer = (*Outlet)(nil)
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutletFactoryWriteLineText(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "forego.log")
+
+	of := NewOutletFactory()
+	of.Padding = 5
+	of.LogFile = logFile
+
+	of.writeLine("web.1", 0, false, 123, "listening on port 5000")
+
+	got := readFile(t, logFile)
+	want := "web.1 | listening on port 5000\n"
+	if got != want {
+		t.Errorf("log line = %q, want %q", got, want)
+	}
+}
+
+func TestOutletFactoryWriteLineJSON(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "forego.log")
+
+	of := NewOutletFactory()
+	of.Format = "json"
+	of.LogFile = logFile
+
+	of.writeLine("web.2", 0, true, 456, "boom")
+
+	var rec jsonRecord
+	line := strings.TrimSpace(readFile(t, logFile))
+	if err := json.Unmarshal([]byte(line), &rec); err != nil {
+		t.Fatalf("unmarshaling %q: %s", line, err)
+	}
+
+	if rec.Process != "web.2" {
+		t.Errorf("Process = %q, want %q", rec.Process, "web.2")
+	}
+	if rec.Stream != "stderr" {
+		t.Errorf("Stream = %q, want %q", rec.Stream, "stderr")
+	}
+	if rec.Msg != "boom" {
+		t.Errorf("Msg = %q, want %q", rec.Msg, "boom")
+	}
+	if rec.Pid != 456 {
+		t.Errorf("Pid = %d, want 456", rec.Pid)
+	}
+}
+
+func TestOutletWriteUsesReplicaNumberNotEntryIndex(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "forego.log")
+
+	of := NewOutletFactory()
+	of.Format = "json"
+	of.LogFile = logFile
+
+	// idx (the Procfile entry's position) is irrelevant here: CreateOutlet's
+	// second argument must be the replica number, so web.2 and web.3 are
+	// distinguishable in their jsonRecord.Replica field.
+	rp := &runningProc{pid: 1}
+	outlet := of.CreateOutlet("web.2", 1, false, rp)
+	outlet.Write([]byte("hello\n"))
+
+	var rec jsonRecord
+	line := strings.TrimSpace(readFile(t, logFile))
+	if err := json.Unmarshal([]byte(line), &rec); err != nil {
+		t.Fatalf("unmarshaling %q: %s", line, err)
+	}
+	if rec.Replica != 1 {
+		t.Errorf("Replica = %d, want 1 (the replica number passed to CreateOutlet)", rec.Replica)
+	}
+}
+
+func TestOutletWriteSplitsMultipleLines(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "forego.log")
+
+	of := NewOutletFactory()
+	of.LogFile = logFile
+
+	outlet := of.CreateOutlet("web.1", 0, false, &runningProc{pid: 1})
+	outlet.Write([]byte("first\nsecond\n"))
+
+	lines := strings.Split(strings.TrimSpace(readFile(t, logFile)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %v", len(lines), lines)
+	}
+	if !strings.HasSuffix(lines[0], "first") || !strings.HasSuffix(lines[1], "second") {
+		t.Errorf("lines = %v, want to end with \"first\" then \"second\"", lines)
+	}
+}
+
+func TestOutletFactoryRotateLockedRenamesAndResets(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "forego.log")
+	if err := os.WriteFile(logFile, []byte("old contents"), 0644); err != nil {
+		t.Fatalf("seeding log file: %s", err)
+	}
+
+	of := NewOutletFactory()
+	of.LogFile = logFile
+	of.LogMaxSize = 1 // 1 MB
+	of.LogMaxBackups = 2
+
+	// Open the file and pretend it's already right at the rotation
+	// threshold, instead of actually writing a megabyte of data.
+	if err := of.rotateLocked(0); err != nil {
+		t.Fatalf("initial rotateLocked: %s", err)
+	}
+	of.fileSize = 1024*1024 - 1
+
+	if err := of.rotateLocked(10); err != nil {
+		t.Fatalf("rotateLocked: %s", err)
+	}
+
+	if _, err := os.Stat(logFile + ".1"); err != nil {
+		t.Errorf("expected %s.1 to exist after rotation: %s", logFile, err)
+	}
+	if of.fileSize != 0 {
+		t.Errorf("fileSize = %d, want 0 on a freshly rotated file", of.fileSize)
+	}
+}
+
+func TestRingBufferWrapsAndSnapshotsInOrder(t *testing.T) {
+	rb := newRingBuffer(3)
+	for _, line := range []string{"a", "b", "c", "d", "e"} {
+		rb.add(line)
+	}
+
+	got := rb.snapshot()
+	want := []string{"c", "d", "e"}
+	if len(got) != len(want) {
+		t.Fatalf("snapshot = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("snapshot[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestOutletFactoryTailLinesLimitsToN(t *testing.T) {
+	of := NewOutletFactory()
+	for _, line := range []string{"one", "two", "three"} {
+		of.recordTail("web.1", line)
+	}
+
+	got := of.TailLines("web.1", 2)
+	want := []string{"two", "three"}
+	if len(got) != len(want) {
+		t.Fatalf("TailLines = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("TailLines[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestOutletFactoryTailLinesUnknownProcess(t *testing.T) {
+	of := NewOutletFactory()
+	if got := of.TailLines("nope", 10); got != nil {
+		t.Errorf("TailLines(\"nope\") = %v, want nil", got)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading %s: %s", path, err)
+	}
+	return string(b)
+}
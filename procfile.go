@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProcfileEntry is one "name: command" line of a Procfile, plus any
+// forego-specific directives declared alongside it after a "##" marker
+// (health_check, depends_on, and the health check's interval/timeout/
+// threshold).
+type ProcfileEntry struct {
+	Name    string
+	Command string
+
+	HealthCheck *HealthCheck
+	DependsOn   []string
+}
+
+// Procfile is a parsed Procfile: an ordered list of entries, since start
+// order and each entry's port offset (idx * 100) are derived from
+// position.
+type Procfile struct {
+	Entries []ProcfileEntry
+}
+
+var procfileLineRe = regexp.MustCompile(`^([A-Za-z0-9_-]+):\s*(.+)$`)
+
+// ReadProcfile parses filename into a Procfile. Each non-blank,
+// non-comment line is "name: command", optionally followed by
+// "## key=value ..." forego directives, e.g.:
+//
+//	web: bundle exec rails server ## health_check=http:/healthz interval=5s depends_on=db
+func ReadProcfile(filename string) (*Procfile, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	pf := &Procfile{}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		entry, err := parseProcfileLine(line)
+		if err != nil {
+			return nil, err
+		}
+		pf.Entries = append(pf.Entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return pf, nil
+}
+
+// parseProcfileLine splits a single Procfile line into its "name: command"
+// and, if present, its "## directives" tail.
+func parseProcfileLine(line string) (ProcfileEntry, error) {
+	rest := line
+	directives := ""
+	if idx := strings.Index(line, "##"); idx >= 0 {
+		rest = strings.TrimSpace(line[:idx])
+		directives = strings.TrimSpace(line[idx+2:])
+	}
+
+	m := procfileLineRe.FindStringSubmatch(rest)
+	if m == nil {
+		return ProcfileEntry{}, fmt.Errorf("bad Procfile entry: %q", line)
+	}
+
+	entry := ProcfileEntry{Name: m[1], Command: strings.TrimSpace(m[2])}
+	if directives != "" {
+		if err := applyDirectives(&entry, directives); err != nil {
+			return ProcfileEntry{}, fmt.Errorf("%s: %s", entry.Name, err)
+		}
+	}
+	return entry, nil
+}
+
+// applyDirectives parses whitespace-separated "key=value" pairs from a
+// Procfile line's "## ..." tail into entry's health check and depends_on
+// fields.
+func applyDirectives(entry *ProcfileEntry, directives string) error {
+	hc := &HealthCheck{Interval: 10 * time.Second, Timeout: 2 * time.Second, FailThreshold: 3}
+	haveHealthCheck := false
+
+	for _, field := range strings.Fields(directives) {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("bad directive %q", field)
+		}
+		key, value := kv[0], kv[1]
+
+		switch key {
+		case "health_check":
+			parts := strings.SplitN(value, ":", 2)
+			hc.Type = parts[0]
+			if len(parts) == 2 {
+				hc.Target = parts[1]
+			}
+			haveHealthCheck = true
+
+		case "interval":
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("interval: %s", err)
+			}
+			hc.Interval = d
+
+		case "timeout":
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("timeout: %s", err)
+			}
+			hc.Timeout = d
+
+		case "threshold":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("threshold: %s", err)
+			}
+			hc.FailThreshold = n
+
+		case "depends_on":
+			entry.DependsOn = strings.Split(value, ",")
+
+		default:
+			return fmt.Errorf("unknown directive %q", key)
+		}
+	}
+
+	if haveHealthCheck {
+		entry.HealthCheck = hc
+	}
+	return nil
+}
+
+// LongestProcessName returns the length of the longest process name, not
+// counting the ".N" replica suffix that log line prefixes carry.
+func (pf *Procfile) LongestProcessName() int {
+	longest := 0
+	for _, entry := range pf.Entries {
+		if len(entry.Name) > longest {
+			longest = len(entry.Name)
+		}
+	}
+	return longest
+}
+
+// HasProcess reports whether name is declared in the Procfile.
+func (pf *Procfile) HasProcess(name string) bool {
+	for _, entry := range pf.Entries {
+		if entry.Name == name {
+			return true
+		}
+	}
+	return false
+}
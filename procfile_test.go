@@ -0,0 +1,156 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseProcfileLineBasic(t *testing.T) {
+	entry, err := parseProcfileLine("web: bundle exec rails server")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if entry.Name != "web" {
+		t.Errorf("Name = %q, want %q", entry.Name, "web")
+	}
+	if entry.Command != "bundle exec rails server" {
+		t.Errorf("Command = %q, want %q", entry.Command, "bundle exec rails server")
+	}
+	if entry.HealthCheck != nil {
+		t.Errorf("HealthCheck = %+v, want nil without a health_check directive", entry.HealthCheck)
+	}
+	if entry.DependsOn != nil {
+		t.Errorf("DependsOn = %v, want nil without a depends_on directive", entry.DependsOn)
+	}
+}
+
+func TestParseProcfileLineWithDirectives(t *testing.T) {
+	entry, err := parseProcfileLine("web: rails server ## health_check=http:/healthz interval=5s timeout=1s threshold=2 depends_on=db,cache")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if entry.HealthCheck == nil {
+		t.Fatal("HealthCheck = nil, want a parsed HealthCheck")
+	}
+	hc := entry.HealthCheck
+	if hc.Type != "http" {
+		t.Errorf("Type = %q, want %q", hc.Type, "http")
+	}
+	if hc.Target != "/healthz" {
+		t.Errorf("Target = %q, want %q", hc.Target, "/healthz")
+	}
+	if hc.Interval != 5*time.Second {
+		t.Errorf("Interval = %s, want 5s", hc.Interval)
+	}
+	if hc.Timeout != time.Second {
+		t.Errorf("Timeout = %s, want 1s", hc.Timeout)
+	}
+	if hc.FailThreshold != 2 {
+		t.Errorf("FailThreshold = %d, want 2", hc.FailThreshold)
+	}
+
+	wantDeps := []string{"db", "cache"}
+	if len(entry.DependsOn) != len(wantDeps) {
+		t.Fatalf("DependsOn = %v, want %v", entry.DependsOn, wantDeps)
+	}
+	for i, dep := range wantDeps {
+		if entry.DependsOn[i] != dep {
+			t.Errorf("DependsOn[%d] = %q, want %q", i, entry.DependsOn[i], dep)
+		}
+	}
+}
+
+func TestParseProcfileLineHealthCheckDefaults(t *testing.T) {
+	entry, err := parseProcfileLine("web: rails server ## health_check=tcp")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if entry.HealthCheck == nil {
+		t.Fatal("HealthCheck = nil, want a parsed HealthCheck")
+	}
+	if entry.HealthCheck.Interval != 10*time.Second {
+		t.Errorf("Interval = %s, want the 10s default", entry.HealthCheck.Interval)
+	}
+	if entry.HealthCheck.Timeout != 2*time.Second {
+		t.Errorf("Timeout = %s, want the 2s default", entry.HealthCheck.Timeout)
+	}
+	if entry.HealthCheck.FailThreshold != 3 {
+		t.Errorf("FailThreshold = %d, want the default of 3", entry.HealthCheck.FailThreshold)
+	}
+}
+
+func TestParseProcfileLineDependsOnWithoutHealthCheck(t *testing.T) {
+	entry, err := parseProcfileLine("worker: do_work ## depends_on=db")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if entry.HealthCheck != nil {
+		t.Errorf("HealthCheck = %+v, want nil: no health_check directive was given", entry.HealthCheck)
+	}
+	if len(entry.DependsOn) != 1 || entry.DependsOn[0] != "db" {
+		t.Errorf("DependsOn = %v, want [db]", entry.DependsOn)
+	}
+}
+
+func TestParseProcfileLineBadLine(t *testing.T) {
+	if _, err := parseProcfileLine("this is not a procfile line"); err == nil {
+		t.Fatal("expected an error for a line without a \"name:\" prefix")
+	}
+}
+
+func TestParseProcfileLineBadDirective(t *testing.T) {
+	if _, err := parseProcfileLine("web: rails server ## not-a-key-value"); err == nil {
+		t.Fatal("expected an error for a directive without \"key=value\"")
+	}
+}
+
+func TestParseProcfileLineUnknownDirective(t *testing.T) {
+	if _, err := parseProcfileLine("web: rails server ## bogus=1"); err == nil {
+		t.Fatal("expected an error for an unrecognized directive key")
+	}
+}
+
+func TestParseProcfileLineBadInterval(t *testing.T) {
+	if _, err := parseProcfileLine("web: rails server ## health_check=tcp interval=not-a-duration"); err == nil {
+		t.Fatal("expected an error for an unparsable interval")
+	}
+}
+
+func TestReadProcfileSkipsBlankAndCommentLines(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Procfile")
+	content := "web: rails server\n\n# a comment\nworker: do_work ## depends_on=web\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("writing test Procfile: %s", err)
+	}
+
+	pf, err := ReadProcfile(path)
+	if err != nil {
+		t.Fatalf("ReadProcfile: %s", err)
+	}
+
+	if len(pf.Entries) != 2 {
+		t.Fatalf("Entries = %v, want 2 entries", pf.Entries)
+	}
+	if pf.Entries[0].Name != "web" || pf.Entries[1].Name != "worker" {
+		t.Errorf("Entries = %+v, want web then worker in order", pf.Entries)
+	}
+}
+
+func TestProcfileHasProcessAndLongestProcessName(t *testing.T) {
+	pf := &Procfile{Entries: []ProcfileEntry{{Name: "web"}, {Name: "worker-longer"}}}
+
+	if !pf.HasProcess("web") {
+		t.Error("HasProcess(\"web\") = false, want true")
+	}
+	if pf.HasProcess("nope") {
+		t.Error("HasProcess(\"nope\") = true, want false")
+	}
+	if got := pf.LongestProcessName(); got != len("worker-longer") {
+		t.Errorf("LongestProcessName() = %d, want %d", got, len("worker-longer"))
+	}
+}
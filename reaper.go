@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+var flagInit bool
+
+// isInitMode reports whether forego should behave like a PID-1 init
+// process: reaping zombie grandchildren in addition to supervising its
+// own direct children. It's on whenever --init is passed, and auto-enabled
+// when the kernel has actually made us PID 1 (the common case inside a
+// container whose entrypoint is `forego start`).
+func isInitMode() bool {
+	return flagInit || os.Getpid() == 1
+}
+
+// reaper implements the PID-1 zombie-reaping pattern: a SIGCHLD handler
+// that loops over syscall.Wait4(-1, ...) to collect every exited child,
+// including ones orphaned by forego's own children forking helpers of
+// their own that are never waited on otherwise. Children forego itself is
+// supervising register here first, so their real exit status is routed
+// back to the goroutine in startProcess that's waiting on them, instead
+// of that Wait4 call stealing it out from under ps.Wait().
+type reaper struct {
+	mu      sync.Mutex
+	waiters map[int]chan syscall.WaitStatus
+}
+
+var zombieReaper = &reaper{waiters: make(map[int]chan syscall.WaitStatus)}
+
+// register must happen before a supervised child can possibly exit, so
+// the reap loop always has somewhere to deliver its status.
+func (r *reaper) register(pid int) chan syscall.WaitStatus {
+	ch := make(chan syscall.WaitStatus, 1)
+	r.mu.Lock()
+	r.waiters[pid] = ch
+	r.mu.Unlock()
+	return ch
+}
+
+func (r *reaper) unregister(pid int) {
+	r.mu.Lock()
+	delete(r.waiters, pid)
+	r.mu.Unlock()
+}
+
+// run installs the SIGCHLD handler and reaps for as long as forego runs.
+// It's only meaningful when isInitMode(), since otherwise nothing
+// re-parents orphaned grandchildren to forego in the first place.
+func (r *reaper) run() {
+	handler := make(chan os.Signal, 1)
+	signal.Notify(handler, syscall.SIGCHLD)
+
+	for range handler {
+		for {
+			var status syscall.WaitStatus
+			pid, err := syscall.Wait4(-1, &status, syscall.WNOHANG, nil)
+			if pid <= 0 || err != nil {
+				break
+			}
+
+			r.mu.Lock()
+			ch, ok := r.waiters[pid]
+			r.mu.Unlock()
+
+			if ok {
+				ch <- status
+			}
+			// Otherwise pid was an orphaned grandchild nobody is
+			// directly waiting on: Wait4 above already reaped it,
+			// which is the whole point of running as init.
+		}
+	}
+}
+
+// wait blocks on ch, as previously returned by register(pid), until pid
+// exits, and returns its wait status the way syscall.Wait4 would have.
+// register must be called synchronously right after the child is started
+// (not from inside wait, which may run in a goroutine scheduled after the
+// child has already exited and been reaped by run's SIGCHLD handler).
+func (r *reaper) wait(pid int, ch chan syscall.WaitStatus) syscall.WaitStatus {
+	defer r.unregister(pid)
+	return <-ch
+}
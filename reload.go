@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// monitorReload watches for SIGHUP and reconciles the running process set
+// against a freshly read Procfile and env file. This lets a Procfile be
+// edited in place without a full ctrl-c/restart cycle.
+func (f *Forego) monitorReload() {
+	handler := make(chan os.Signal, 1)
+	signal.Notify(handler, syscall.SIGHUP)
+
+	for range handler {
+		f.of.SystemOutput("SIGHUP received, reloading Procfile")
+
+		pf, err := ReadProcfile(flagProcfile)
+		if err != nil {
+			f.of.SystemOutput(fmt.Sprintf("reload failed: %s", err))
+			continue
+		}
+
+		env, err := ReadEnv(flagEnv)
+		if err != nil {
+			f.of.SystemOutput(fmt.Sprintf("reload failed: %s", err))
+			continue
+		}
+
+		f.reconcile(pf, env)
+	}
+}
+
+// reconcile brings the set of running processes in line with pf/env:
+// entries that are new are started, entries that were removed are stopped,
+// and entries whose command or environment changed are restarted (an
+// .env-only edit is exactly as much of a "change" as a Procfile edit).
+// Concurrency counts are re-applied throughout, from f.scaled: the -c flag
+// value as most recently overridden by `forego scale`, so a reload doesn't
+// undo a scale done at runtime. Starts are routed through startWhenReady,
+// same as runStart, so a reloaded process still waits on its depends_on.
+func (f *Forego) reconcile(pf *Procfile, env Env) {
+	f.mu.Lock()
+	f.pf = pf
+	f.env = env
+	concurrency := f.scaled
+	f.mu.Unlock()
+
+	for idx, proc := range pf.Entries {
+		numProcs := 1
+		if value, ok := concurrency[proc.Name]; ok {
+			numProcs = value
+		}
+
+		for i := 0; i < numProcs; i++ {
+			key := procKey{proc.Name, i}
+
+			f.mu.Lock()
+			running, ok := f.procs[key]
+			f.mu.Unlock()
+
+			switch {
+			case !ok:
+				f.of.SystemOutput(fmt.Sprintf("%s.%d is new, starting", proc.Name, i+1))
+				f.wg.Add(1)
+				go f.startWhenReady(idx, i, proc, env, f.of)
+
+			case running.entry.Command != proc.Command || !envEqual(running.env, env):
+				f.of.SystemOutput(fmt.Sprintf("%s.%d changed, restarting", proc.Name, i+1))
+				f.stopIfCurrent(key, running)
+				f.waitForExit(running)
+				f.wg.Add(1)
+				go f.startWhenReady(idx, i, proc, env, f.of)
+			}
+		}
+
+		f.stopReplicasFrom(proc.Name, numProcs)
+	}
+
+	f.stopEntriesNotIn(pf)
+}
+
+// envEqual reports whether a and b hold exactly the same variables.
+func envEqual(a, b Env) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// stopReplicasFrom stops any running replicas of name at or beyond from,
+// e.g. after the concurrency count for a process type is lowered.
+func (f *Forego) stopReplicasFrom(name string, from int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for key, running := range f.procs {
+		if key.name == name && key.num >= from {
+			f.of.SystemOutput(fmt.Sprintf("%s.%d removed, stopping", name, key.num+1))
+			running.closeStop()
+		}
+	}
+}
+
+// stopEntriesNotIn stops every running process whose name no longer
+// appears in pf at all.
+func (f *Forego) stopEntriesNotIn(pf *Procfile) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for key, running := range f.procs {
+		if !pf.HasProcess(key.name) {
+			f.of.SystemOutput(fmt.Sprintf("%s.%d removed, stopping", key.name, key.num+1))
+			running.closeStop()
+		}
+	}
+}
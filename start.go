@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -17,10 +18,16 @@ const shutdownGraceTime = 3 * time.Second
 var flagPort int
 var flagConcurrency string
 var flagRestart bool
+var flagOnFailure string
+var flagLogFormat string
+var flagLogFile string
+var flagLogMaxSize int
+var flagLogMaxBackups int
+var flagControlSock string
 
 var cmdStart = &Command{
 	Run:   runStart,
-	Usage: "start [process name] [-f procfile] [-e env] [-c concurrency] [-p port] [-r]",
+	Usage: "start [process name] [-f procfile] [-e env] [-c concurrency] [-p port] [-r] [--init] [--log-format text|json] [--log-file path]",
 	Short: "Start the application",
 	Long: `
 Start the application specified by a Procfile (defaults to ./Procfile)
@@ -39,6 +46,22 @@ func init() {
 	cmdStart.Flag.IntVar(&flagPort, "p", 5000, "port")
 	cmdStart.Flag.StringVar(&flagConcurrency, "c", "", "concurrency")
 	cmdStart.Flag.BoolVar(&flagRestart, "r", false, "restart")
+	cmdStart.Flag.StringVar(&flagOnFailure, "on-failure", "shutdown", "action when a process exceeds its restart budget: shutdown or continue")
+	cmdStart.Flag.BoolVar(&flagInit, "init", false, "reap zombie processes (auto-enabled when running as PID 1)")
+	cmdStart.Flag.StringVar(&flagLogFormat, "log-format", envOr("FOREGO_LOG_FORMAT", "text"), "log output format: text or json")
+	cmdStart.Flag.StringVar(&flagLogFile, "log-file", envOr("FOREGO_LOG_FILE", ""), "write logs to this file instead of stdout/stderr, with size-based rotation")
+	cmdStart.Flag.IntVar(&flagLogMaxSize, "log-max-size", 10, "max size in MB of a log file before it's rotated")
+	cmdStart.Flag.IntVar(&flagLogMaxBackups, "log-max-backups", 5, "number of rotated log files to keep")
+	cmdStart.Flag.StringVar(&flagControlSock, "control-sock", "", "listen on this unix socket for `forego ps/restart/scale/kill`")
+}
+
+// envOr returns the value of the named environment variable, or def if
+// it's unset, so flags like --log-format can be set via env var too.
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
 }
 
 func parseConcurrency(value string) (map[string]int, error) {
@@ -69,12 +92,121 @@ func parseConcurrency(value string) (map[string]int, error) {
 	return concurrency, nil
 }
 
+// procKey identifies a single running replica of a Procfile entry.
+type procKey struct {
+	name string
+	num  int
+}
+
+// runningProc is bookkeeping for a replica that startProcess has launched,
+// kept around so a reload can tell whether it needs to be started, left
+// alone, or restarted.
+type runningProc struct {
+	entry     ProcfileEntry
+	env       Env           // the environment this replica was started with
+	stop      chan struct{} // closed to stop just this one replica
+	stopOnce  sync.Once     // guards stop: reload and a control-socket request can race on the same replica
+	exited    chan struct{} // closed once the replica's process has actually exited
+	pid       int
+	startedAt time.Time
+	state     string // "running", "unhealthy", "restarting", "stopping", or "failed"
+}
+
+// closeStop asks the replica to stop, exactly once: closing an
+// already-closed channel panics, and a SIGHUP reload and a
+// `forego restart`/`forego scale` can plausibly race on the same replica.
+func (rp *runningProc) closeStop() {
+	rp.stopOnce.Do(func() { close(rp.stop) })
+}
+
 type Forego struct {
 	shutdown    sync.Once     // Closes teardown exactly once
 	teardown    chan struct{} // barrier: closed when shutting down
 	teardownNow chan struct{} // barrier: second CTRL-C. More urgent.
 
 	wg sync.WaitGroup
+
+	mu    sync.Mutex
+	procs map[procKey]*runningProc // currently running replicas, by key
+	pf    *Procfile                // most recently loaded Procfile
+	env   Env                      // most recently loaded environment
+	of    *OutletFactory
+
+	ready map[string]chan struct{} // closed once a process name is ready
+
+	backoff map[procKey]*backoffState // crash-loop backoff, per replica
+
+	scaled map[string]int // concurrency, by process name; -c plus any later `forego scale`
+}
+
+// registerProcess records that a replica identified by key is now running,
+// so that a later reload or control-socket request can inspect or diff
+// against it. It returns the registry entry so the caller can later
+// unregister precisely this incarnation of key.
+func (f *Forego) registerProcess(key procKey, entry ProcfileEntry, env Env, stop, exited chan struct{}) *runningProc {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	rp := &runningProc{entry: entry, env: env, stop: stop, exited: exited, state: "running"}
+	f.procs[key] = rp
+	return rp
+}
+
+// unregisterProcess forgets a replica once it has exited for good (i.e. it
+// won't be immediately restarted). It only removes rp itself: if key has
+// already been re-registered for a newer incarnation (a restart raced
+// ahead of this one's teardown), that newer entry is left alone.
+func (f *Forego) unregisterProcess(key procKey, rp *runningProc) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.procs[key] == rp {
+		delete(f.procs, key)
+	}
+}
+
+// stopIfCurrent closes rp's stop channel only if it's still the entry
+// registered under key, mirroring unregisterProcess's identity check. A
+// reload and a control-socket request can both have read the same
+// *runningProc before either acted on it; re-checking under f.mu (and
+// closeStop's sync.Once) means at most one of them actually signals it.
+func (f *Forego) stopIfCurrent(key procKey, rp *runningProc) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.procs[key] == rp {
+		rp.closeStop()
+	}
+}
+
+// waitForExit blocks until rp's process has actually exited, or forego
+// starts tearing down. Closing a replica's stop channel only *asks* it to
+// exit; per terminateProcess's SIGTERM-then-grace-period policy that can
+// take up to shutdownGraceTime, so a caller about to start a replacement
+// on the same port must wait here first or the replacement's bind will
+// race the old process's.
+func (f *Forego) waitForExit(rp *runningProc) {
+	select {
+	case <-rp.exited:
+	case <-f.teardown:
+	}
+}
+
+// setPID records the OS pid and start time of an already-registered
+// replica, once its process has actually been spawned. Used for `forego
+// ps` and friends.
+func (f *Forego) setPID(key procKey, rp *runningProc, pid int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	rp.pid = pid
+	rp.startedAt = time.Now()
+}
+
+// setState records key's current lifecycle state, for `forego ps`. It's a
+// no-op once the replica has already been unregistered.
+func (f *Forego) setState(key procKey, state string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if rp, ok := f.procs[key]; ok {
+		rp.state = state
+	}
 }
 
 func (f *Forego) SignalShutdown() {
@@ -102,6 +234,32 @@ func (f *Forego) monitorInterrupt() {
 	}
 }
 
+// terminateProcess asks ps to exit (SIGTERM, then SIGKILL after
+// shutdownGraceTime or an urgent second ctrl-c) and waits for it to do so.
+func (f *Forego) terminateProcess(procName string, ps *Process, finished chan struct{}, of *OutletFactory) {
+	if !osHaveSigTerm {
+		of.SystemOutput(fmt.Sprintf("Killing %s", procName))
+		ps.cmd.Process.Kill()
+		<-finished
+		return
+	}
+
+	of.SystemOutput(fmt.Sprintf("sending SIGTERM to %s", procName))
+	ps.SendSigTerm()
+
+	// Give the process a chance to exit, otherwise kill it.
+	select {
+	case <-time.After(shutdownGraceTime):
+		of.SystemOutput(fmt.Sprintf("Killing %s", procName))
+		ps.SendSigKill()
+	case <-f.teardownNow:
+		of.SystemOutput(fmt.Sprintf("Killing %s", procName))
+		ps.SendSigKill()
+	case <-finished:
+	}
+	<-finished
+}
+
 func (f *Forego) startProcess(idx, procNum int, proc ProcfileEntry, env Env, of *OutletFactory) {
 	port := flagPort + (idx * 100)
 
@@ -110,22 +268,51 @@ func (f *Forego) startProcess(idx, procNum int, proc ProcfileEntry, env Env, of
 	ps.Env["PORT"] = strconv.Itoa(port)
 	ps.Root = filepath.Dir(flagProcfile)
 	ps.Stdin = nil
-	ps.Stdout = of.CreateOutlet(procName, idx, false)
-	ps.Stderr = of.CreateOutlet(procName, idx, true)
-
-	of.SystemOutput(fmt.Sprintf("starting %s on port %d", procName, port))
 
+	key := procKey{proc.Name, procNum}
+	stop := make(chan struct{})
 	finished := make(chan struct{}) // closed on process exit
+	rp := f.registerProcess(key, proc, env, stop, finished)
+	f.backoffFor(key).recordStart()
+
+	ps.Stdout = of.CreateOutlet(procName, procNum, false, rp)
+	ps.Stderr = of.CreateOutlet(procName, procNum, true, rp)
+
+	of.SystemOutput(fmt.Sprintf("starting %s on port %d", procName, port))
 
 	ps.Start()
+	f.setPID(key, rp, ps.cmd.Process.Pid)
+
+	// Register with the reaper synchronously, before any goroutine
+	// scheduling gap: the child could otherwise exit and be reaped by
+	// run's SIGCHLD handler before a later goroutine got around to
+	// registering, losing the exit status and hanging wait() forever.
+	var reaped chan syscall.WaitStatus
+	if isInitMode() {
+		reaped = zombieReaper.register(ps.cmd.Process.Pid)
+	}
+
 	go func() {
 		defer close(finished)
-		ps.Wait()
+		if isInitMode() {
+			zombieReaper.wait(ps.cmd.Process.Pid, reaped)
+		} else {
+			ps.Wait()
+		}
 	}()
 
+	unhealthy := make(chan struct{}) // closed if the health check gives up on this replica
+	if proc.HealthCheck != nil {
+		go f.monitorHealth(procName, key, port, proc.HealthCheck, unhealthy, finished, of)
+	} else {
+		// No health check declared: the replica is ready as soon as it's started.
+		f.markReady(proc.Name)
+	}
+
 	f.wg.Add(1)
 	go func() {
 		defer f.wg.Done()
+		defer f.unregisterProcess(key, rp)
 
 		// Prevent goroutine from exiting before process has finished.
 		defer func() { <-finished }()
@@ -133,36 +320,61 @@ func (f *Forego) startProcess(idx, procNum int, proc ProcfileEntry, env Env, of
 		select {
 		case <-finished:
 			if flagRestart {
-				f.startProcess(idx, procNum, proc, env, of)
+				if f.restart(key, procName, of) {
+					f.startProcess(idx, procNum, proc, env, of)
+				}
 				return
 			} else {
 				f.SignalShutdown()
 			}
 
+		case <-unhealthy:
+			if flagRestart {
+				f.terminateProcess(procName, ps, finished, of)
+				if f.restart(key, procName, of) {
+					f.startProcess(idx, procNum, proc, env, of)
+				}
+			} else {
+				// Left running, marked unhealthy: still honour a stop
+				// request or teardown for it.
+				select {
+				case <-finished:
+				case <-stop:
+					f.setState(key, "stopping")
+					f.terminateProcess(procName, ps, finished, of)
+				case <-f.teardown:
+					f.terminateProcess(procName, ps, finished, of)
+				}
+			}
+
+		case <-stop:
+			// A reload decided this replica should no longer run.
+			f.setState(key, "stopping")
+			f.terminateProcess(procName, ps, finished, of)
+
 		case <-f.teardown:
 			// Forego tearing down
+			f.terminateProcess(procName, ps, finished, of)
+		}
+	}()
+}
 
-			if !osHaveSigTerm {
-				of.SystemOutput(fmt.Sprintf("Killing %s", procName))
-				ps.cmd.Process.Kill()
-				return
-			}
+// startWhenReady blocks until every process proc.DependsOn has reported
+// ready (or forego starts tearing down, in which case it gives up), then
+// starts proc. This is the dependency-graph scheduler that gives
+// depends_on its topological start order, without forcing runStart itself
+// to block entry-by-entry.
+func (f *Forego) startWhenReady(idx, procNum int, proc ProcfileEntry, env Env, of *OutletFactory) {
+	defer f.wg.Done()
 
-			of.SystemOutput(fmt.Sprintf("sending SIGTERM to %s", procName))
-			ps.SendSigTerm()
-
-			// Give the process a chance to exit, otherwise kill it.
-			select {
-			case <-time.After(shutdownGraceTime):
-				of.SystemOutput(fmt.Sprintf("Killing %s", procName))
-				ps.SendSigKill()
-			case <-f.teardownNow:
-				of.SystemOutput(fmt.Sprintf("Killing %s", procName))
-				ps.SendSigKill()
-			case <-finished:
-			}
+	for _, dep := range proc.DependsOn {
+		select {
+		case <-f.readyChan(dep):
+		case <-f.teardown:
+			return
 		}
-	}()
+	}
+	f.startProcess(idx, procNum, proc, env, of)
 }
 
 func runStart(cmd *Command, args []string) {
@@ -183,13 +395,34 @@ func runStart(cmd *Command, args []string) {
 
 	of := NewOutletFactory()
 	of.Padding = pf.LongestProcessName()
+	of.Format = flagLogFormat
+	if flagLogFile != "" {
+		of.LogFile = flagLogFile
+		of.LogMaxSize = flagLogMaxSize
+		of.LogMaxBackups = flagLogMaxBackups
+	}
 
 	f := &Forego{
 		teardown:    make(chan struct{}),
 		teardownNow: make(chan struct{}),
+		procs:       make(map[procKey]*runningProc),
+		pf:          pf,
+		env:         env,
+		of:          of,
+		scaled:      concurrency,
 	}
 
 	go f.monitorInterrupt()
+	go f.monitorReload()
+
+	if isInitMode() {
+		of.SystemOutput("running as init, reaping zombie processes")
+		go zombieReaper.run()
+	}
+
+	if flagControlSock != "" {
+		go f.serveControlSock()
+	}
 
 	var singleton string = ""
 	if len(args) > 0 {
@@ -206,7 +439,8 @@ func runStart(cmd *Command, args []string) {
 		}
 		for i := 0; i < numProcs; i++ {
 			if (singleton == "") || (singleton == proc.Name) {
-				f.startProcess(idx, i, proc, env, of)
+				f.wg.Add(1)
+				go f.startWhenReady(idx, i, proc, env, of)
 			}
 		}
 	}